@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
@@ -11,7 +12,7 @@ import (
 	"time"
 )
 
-type CFTranscriber struct {
+type CloudflareTranscriber struct {
 	AccountID string
 	APIToken  string
 	Model     string
@@ -19,7 +20,7 @@ type CFTranscriber struct {
 	BaseURL   string
 }
 
-func NewCFTranscriber(accountID, apiToken, model, language string) *CFTranscriber {
+func NewCloudflareTranscriber(accountID, apiToken, model, language string) *CloudflareTranscriber {
 	if model == "" {
 		model = "@cf/openai/whisper-large-v3-turbo"
 	}
@@ -27,7 +28,7 @@ func NewCFTranscriber(accountID, apiToken, model, language string) *CFTranscribe
 		language = "en"
 	}
 	baseURL := fmt.Sprintf("https://api.cloudflare.com/client/v4/accounts/%s/ai/run/%s", accountID, model)
-	return &CFTranscriber{
+	return &CloudflareTranscriber{
 		AccountID: accountID,
 		APIToken:  apiToken,
 		Model:     model,
@@ -36,7 +37,26 @@ func NewCFTranscriber(accountID, apiToken, model, language string) *CFTranscribe
 	}
 }
 
-func (cf *CFTranscriber) encodeAudioFile(filePath string) (string, error) {
+func (cf *CloudflareTranscriber) Name() string { return "cloudflare" }
+
+func (cf *CloudflareTranscriber) Transcribe(ctx context.Context, audioPath string, opts TranscribeOptions) (TranscribeResult, error) {
+	language := opts.Language
+	if language == "" {
+		language = cf.Language
+	}
+	result, err := cf.transcribeRaw(ctx, audioPath, language)
+	if err != nil {
+		return TranscribeResult{}, err
+	}
+	if res, ok := result["result"].(map[string]interface{}); ok {
+		if text, ok := res["text"].(string); ok {
+			return TranscribeResult{Text: text}, nil
+		}
+	}
+	return TranscribeResult{}, fmt.Errorf("transcription text not found in response")
+}
+
+func (cf *CloudflareTranscriber) encodeAudioFile(filePath string) (string, error) {
 	data, err := ioutil.ReadFile(filePath)
 	if err != nil {
 		return "", err
@@ -44,7 +64,7 @@ func (cf *CFTranscriber) encodeAudioFile(filePath string) (string, error) {
 	return base64.StdEncoding.EncodeToString(data), nil
 }
 
-func (cf *CFTranscriber) Transcribe(audioPath string, language string) (map[string]interface{}, error) {
+func (cf *CloudflareTranscriber) transcribeRaw(ctx context.Context, audioPath string, language string) (map[string]interface{}, error) {
 	encodedAudio, err := cf.encodeAudioFile(audioPath)
 	if err != nil {
 		return nil, err
@@ -63,7 +83,7 @@ func (cf *CFTranscriber) Transcribe(audioPath string, language string) (map[stri
 		return nil, err
 	}
 
-	req, err := http.NewRequest("POST", cf.BaseURL, bytes.NewBuffer(payloadBytes))
+	req, err := http.NewRequestWithContext(ctx, "POST", cf.BaseURL, bytes.NewBuffer(payloadBytes))
 	if err != nil {
 		return nil, err
 	}
@@ -90,7 +110,8 @@ func (cf *CFTranscriber) Transcribe(audioPath string, language string) (map[stri
 	return result, nil
 }
 
-// CfTranscribe is a helper function that loads credentials from .env and transcribes the audio.
+// CfTranscribe is a compatibility helper that loads credentials from the environment and
+// transcribes the audio.
 func CfTranscribe(audioPath, model, language string) (string, error) {
 	accountID := os.Getenv("CF_ACCOUNT_ID")
 	apiToken := os.Getenv("CF_API_KEY")
@@ -98,16 +119,10 @@ func CfTranscribe(audioPath, model, language string) (string, error) {
 		return "", fmt.Errorf("please set CF_ACCOUNT_ID and CF_API_KEY environment variables")
 	}
 
-	transcriber := NewCFTranscriber(accountID, apiToken, model, language)
-	result, err := transcriber.Transcribe(audioPath, language)
+	transcriber := NewCloudflareTranscriber(accountID, apiToken, model, language)
+	result, err := transcriber.Transcribe(context.Background(), audioPath, TranscribeOptions{Language: language})
 	if err != nil {
 		return "", err
 	}
-	// Assuming the response JSON contains result.text
-	if res, ok := result["result"].(map[string]interface{}); ok {
-		if text, ok := res["text"].(string); ok {
-			return text, nil
-		}
-	}
-	return "", fmt.Errorf("transcription text not found in response")
+	return result.Text, nil
 }
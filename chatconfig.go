@@ -0,0 +1,244 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// watchForReload reloads numberACL from aclPath whenever the process receives SIGHUP, so the
+// allow/deny list can be edited without a restart.
+func watchForReload(aclPath string) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			log.Println("Received SIGHUP, reloading ACL")
+			if err := numberACL.Reload(aclPath); err != nil {
+				log.Printf("Failed to reload ACL: %v", err)
+			}
+		}
+	}()
+}
+
+// ChatSettings holds the per-chat transcription preferences stored in the chat config DB.
+type ChatSettings struct {
+	Enabled          bool
+	Language         string
+	Prompt           string
+	MinDurationSec   int
+	ReplyStyle       string // "reply", "quote" or "dm"
+	ReactionsEnabled bool
+}
+
+// defaultChatSettings is used for any chat that hasn't been configured yet.
+func defaultChatSettings() ChatSettings {
+	return ChatSettings{
+		Enabled:          true,
+		Language:         "pt",
+		Prompt:           WHISPER_PROMPT,
+		MinDurationSec:   0,
+		ReplyStyle:       "reply",
+		ReactionsEnabled: false,
+	}
+}
+
+// ChatConfigStore persists per-JID chat settings in a small SQLite table.
+type ChatConfigStore struct {
+	db *sql.DB
+	mu sync.Mutex
+}
+
+func NewChatConfigStore(path string) (*ChatConfigStore, error) {
+	db, err := sql.Open("sqlite3", fmt.Sprintf("file:%s?_foreign_keys=on", path))
+	if err != nil {
+		return nil, fmt.Errorf("open chat config store: %w", err)
+	}
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS chat_settings (
+		jid TEXT PRIMARY KEY,
+		enabled INTEGER NOT NULL DEFAULT 1,
+		language TEXT NOT NULL DEFAULT 'pt',
+		prompt TEXT NOT NULL DEFAULT '',
+		min_duration_sec INTEGER NOT NULL DEFAULT 0,
+		reply_style TEXT NOT NULL DEFAULT 'reply',
+		reactions_enabled INTEGER NOT NULL DEFAULT 0
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("create chat_settings table: %w", err)
+	}
+	return &ChatConfigStore{db: db}, nil
+}
+
+// Get returns the settings for jid, falling back to the defaults if it hasn't been configured.
+func (s *ChatConfigStore) Get(jid string) (ChatSettings, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	row := s.db.QueryRow(`SELECT enabled, language, prompt, min_duration_sec, reply_style, reactions_enabled FROM chat_settings WHERE jid = ?`, jid)
+	var settings ChatSettings
+	err := row.Scan(&settings.Enabled, &settings.Language, &settings.Prompt, &settings.MinDurationSec, &settings.ReplyStyle, &settings.ReactionsEnabled)
+	if err == sql.ErrNoRows {
+		return defaultChatSettings(), nil
+	}
+	if err != nil {
+		return ChatSettings{}, fmt.Errorf("get chat settings for %s: %w", jid, err)
+	}
+	return settings, nil
+}
+
+// Set upserts the settings for jid.
+func (s *ChatConfigStore) Set(jid string, settings ChatSettings) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.db.Exec(`INSERT INTO chat_settings (jid, enabled, language, prompt, min_duration_sec, reply_style, reactions_enabled)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(jid) DO UPDATE SET enabled=excluded.enabled, language=excluded.language,
+			prompt=excluded.prompt, min_duration_sec=excluded.min_duration_sec, reply_style=excluded.reply_style,
+			reactions_enabled=excluded.reactions_enabled`,
+		jid, settings.Enabled, settings.Language, settings.Prompt, settings.MinDurationSec, settings.ReplyStyle, settings.ReactionsEnabled)
+	if err != nil {
+		return fmt.Errorf("set chat settings for %s: %w", jid, err)
+	}
+	return nil
+}
+
+// ACL is an allow-list or deny-list of phone numbers, reloadable from disk on SIGHUP.
+type ACL struct {
+	mu      sync.RWMutex
+	mode    string // "allow" or "deny"
+	numbers map[string]bool
+}
+
+// LoadACL reads an ACL file. The first non-empty line may be "mode: allow" or "mode: deny"
+// (deny is the default, matching the previous EXCLUDED_NUMBERS behavior); the rest of the file
+// is one phone number per line.
+func LoadACL(path string) (*ACL, error) {
+	acl := &ACL{mode: "deny", numbers: make(map[string]bool)}
+	if err := acl.Reload(path); err != nil {
+		return nil, err
+	}
+	return acl, nil
+}
+
+// Reload re-reads the ACL file in place, so a running process can pick up edits on SIGHUP.
+func (a *ACL) Reload(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("reload ACL %s: %w", path, err)
+	}
+
+	mode := "deny"
+	numbers := make(map[string]bool)
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if rest, ok := strings.CutPrefix(line, "mode:"); ok {
+			mode = strings.TrimSpace(rest)
+			continue
+		}
+		numbers[line] = true
+	}
+
+	a.mu.Lock()
+	a.mode = mode
+	a.numbers = numbers
+	a.mu.Unlock()
+	return nil
+}
+
+// Allowed reports whether number may be transcribed under this ACL.
+func (a *ACL) Allowed(number string) bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	listed := a.numbers[number]
+	if a.mode == "allow" {
+		return listed
+	}
+	return !listed
+}
+
+// adminCommandPrefix is the in-chat command prefix recognized by parseAdminCommand.
+const adminCommandPrefix = "!transcribe"
+
+// parseAdminCommand splits a "!transcribe <subcommand> [args]" message into its parts.
+func parseAdminCommand(text string) (subcommand string, args string, ok bool) {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, adminCommandPrefix) {
+		return "", "", false
+	}
+	rest := strings.TrimSpace(strings.TrimPrefix(text, adminCommandPrefix))
+	if rest == "" {
+		return "status", "", true
+	}
+	parts := strings.SplitN(rest, " ", 2)
+	subcommand = parts[0]
+	if len(parts) == 2 {
+		args = strings.TrimSpace(parts[1])
+	}
+	return subcommand, args, true
+}
+
+// applyAdminCommand mutates settings according to subcommand/args, returning the reply text to
+// send back to the chat.
+func applyAdminCommand(settings *ChatSettings, subcommand, args string) (reply string, err error) {
+	switch subcommand {
+	case "on":
+		settings.Enabled = true
+		return "Transcrição ativada neste chat.", nil
+	case "off":
+		settings.Enabled = false
+		return "Transcrição desativada neste chat.", nil
+	case "lang":
+		if args == "" {
+			return "", fmt.Errorf("uso: !transcribe lang <idioma>")
+		}
+		settings.Language = args
+		return fmt.Sprintf("Idioma definido para %s.", args), nil
+	case "prompt":
+		settings.Prompt = args
+		return "Prompt de transcrição atualizado.", nil
+	case "mindur":
+		seconds, err := strconv.Atoi(args)
+		if err != nil {
+			return "", fmt.Errorf("uso: !transcribe mindur <segundos>")
+		}
+		settings.MinDurationSec = seconds
+		return fmt.Sprintf("Duração mínima definida para %ds.", seconds), nil
+	case "style":
+		if args != "reply" && args != "quote" && args != "dm" {
+			return "", fmt.Errorf("uso: !transcribe style <reply|quote|dm>")
+		}
+		settings.ReplyStyle = args
+		return fmt.Sprintf("Estilo de resposta definido para %s.", args), nil
+	case "reactions":
+		switch args {
+		case "on":
+			settings.ReactionsEnabled = true
+			return "Reações ativadas.", nil
+		case "off":
+			settings.ReactionsEnabled = false
+			return "Reações desativadas.", nil
+		default:
+			return "", fmt.Errorf("uso: !transcribe reactions <on|off>")
+		}
+	case "status":
+		return fmt.Sprintf("enabled=%v language=%s min_duration_sec=%d reply_style=%s reactions_enabled=%v",
+			settings.Enabled, settings.Language, settings.MinDurationSec, settings.ReplyStyle, settings.ReactionsEnabled), nil
+	default:
+		return "", fmt.Errorf("comando desconhecido: %s", subcommand)
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import "testing"
+
+func TestParseAdminCommand(t *testing.T) {
+	tests := []struct {
+		name           string
+		text           string
+		wantSubcommand string
+		wantArgs       string
+		wantOk         bool
+	}{
+		{name: "not a command", text: "hello there", wantOk: false},
+		{name: "bare prefix defaults to status", text: "!transcribe", wantSubcommand: "status", wantOk: true},
+		{name: "bare prefix with trailing space defaults to status", text: "!transcribe   ", wantSubcommand: "status", wantOk: true},
+		{name: "subcommand with no args", text: "!transcribe on", wantSubcommand: "on", wantOk: true},
+		{name: "subcommand with args", text: "!transcribe lang en", wantSubcommand: "lang", wantArgs: "en", wantOk: true},
+		{name: "subcommand with multi-word args", text: "!transcribe prompt be extra literal", wantSubcommand: "prompt", wantArgs: "be extra literal", wantOk: true},
+		{name: "leading/trailing whitespace is trimmed", text: "  !transcribe off  ", wantSubcommand: "off", wantOk: true},
+		{name: "prefix must match exactly, not just contain it", text: "please !transcribe on", wantOk: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			subcommand, args, ok := parseAdminCommand(tt.text)
+			if ok != tt.wantOk {
+				t.Fatalf("parseAdminCommand(%q) ok = %v, want %v", tt.text, ok, tt.wantOk)
+			}
+			if !ok {
+				return
+			}
+			if subcommand != tt.wantSubcommand {
+				t.Errorf("parseAdminCommand(%q) subcommand = %q, want %q", tt.text, subcommand, tt.wantSubcommand)
+			}
+			if args != tt.wantArgs {
+				t.Errorf("parseAdminCommand(%q) args = %q, want %q", tt.text, args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestACLAllowed(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    string
+		numbers map[string]bool
+		number  string
+		want    bool
+	}{
+		{name: "deny mode blocks a listed number", mode: "deny", numbers: map[string]bool{"5511999999999": true}, number: "5511999999999", want: false},
+		{name: "deny mode allows an unlisted number", mode: "deny", numbers: map[string]bool{"5511999999999": true}, number: "5511888888888", want: true},
+		{name: "deny mode with empty list allows everyone", mode: "deny", numbers: map[string]bool{}, number: "5511888888888", want: true},
+		{name: "allow mode permits a listed number", mode: "allow", numbers: map[string]bool{"5511999999999": true}, number: "5511999999999", want: true},
+		{name: "allow mode blocks an unlisted number", mode: "allow", numbers: map[string]bool{"5511999999999": true}, number: "5511888888888", want: false},
+		{name: "allow mode with empty list blocks everyone", mode: "allow", numbers: map[string]bool{}, number: "5511888888888", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			acl := &ACL{mode: tt.mode, numbers: tt.numbers}
+			if got := acl.Allowed(tt.number); got != tt.want {
+				t.Errorf("Allowed(%q) = %v, want %v", tt.number, got, tt.want)
+			}
+		})
+	}
+}
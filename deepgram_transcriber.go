@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// DeepgramTranscriber calls Deepgram's prerecorded REST endpoint.
+type DeepgramTranscriber struct {
+	APIKey string
+	Model  string
+}
+
+func NewDeepgramTranscriber(apiKey string) *DeepgramTranscriber {
+	return &DeepgramTranscriber{APIKey: apiKey, Model: "nova-2"}
+}
+
+func (d *DeepgramTranscriber) Name() string { return "deepgram" }
+
+func (d *DeepgramTranscriber) Transcribe(ctx context.Context, audioPath string, opts TranscribeOptions) (TranscribeResult, error) {
+	if d.APIKey == "" {
+		return TranscribeResult{}, fmt.Errorf("DEEPGRAM_API_KEY not set in environment")
+	}
+
+	audioData, err := os.ReadFile(audioPath)
+	if err != nil {
+		return TranscribeResult{}, fmt.Errorf("audio file not found: %w", err)
+	}
+
+	model := d.Model
+	if model == "" {
+		model = "nova-2"
+	}
+	query := url.Values{}
+	query.Set("model", model)
+	query.Set("punctuate", "true")
+	query.Set("smart_format", "true")
+	if opts.Language != "" {
+		query.Set("language", opts.Language)
+	}
+	endpoint := "https://api.deepgram.com/v1/listen?" + query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(audioData))
+	if err != nil {
+		return TranscribeResult{}, err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Token %s", d.APIKey))
+	req.Header.Set("Content-Type", "audio/ogg")
+
+	client := &http.Client{Timeout: 60 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return TranscribeResult{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := ioutil.ReadAll(resp.Body)
+		return TranscribeResult{}, fmt.Errorf("transcription failed: %s", string(bodyBytes))
+	}
+
+	var response struct {
+		Results struct {
+			Channels []struct {
+				Alternatives []struct {
+					Transcript string `json:"transcript"`
+				} `json:"alternatives"`
+			} `json:"channels"`
+		} `json:"results"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return TranscribeResult{}, err
+	}
+	if len(response.Results.Channels) == 0 || len(response.Results.Channels[0].Alternatives) == 0 {
+		return TranscribeResult{}, fmt.Errorf("transcription text not found in response")
+	}
+
+	return TranscribeResult{Text: response.Results.Channels[0].Alternatives[0].Transcript}, nil
+}
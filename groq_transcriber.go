@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -12,12 +13,37 @@ import (
 	"time"
 )
 
+// GroqTranscriber calls Groq's hosted Whisper endpoint.
+type GroqTranscriber struct {
+	APIKey string
+	Model  string
+}
+
+func NewGroqTranscriber(apiKey string) *GroqTranscriber {
+	return &GroqTranscriber{APIKey: apiKey, Model: "whisper-large-v3"}
+}
+
+func (g *GroqTranscriber) Name() string { return "groq" }
+
+func (g *GroqTranscriber) Transcribe(ctx context.Context, audioPath string, opts TranscribeOptions) (TranscribeResult, error) {
+	text, err := transcribeAudioGroq(ctx, g.APIKey, g.Model, audioPath, opts.Prompt, opts.Language)
+	if err != nil {
+		return TranscribeResult{}, err
+	}
+	return TranscribeResult{Text: text}, nil
+}
+
+// TranscribeAudioGroq is a compatibility helper that loads GROQ_API_KEY from the environment and
+// transcribes the given audio file.
 func TranscribeAudioGroq(audioPath, prompt, language string) (string, error) {
 	apiKey := os.Getenv("GROQ_API_KEY")
 	if apiKey == "" {
 		return "", fmt.Errorf("GROQ_API_KEY not set in environment")
 	}
+	return transcribeAudioGroq(context.Background(), apiKey, "whisper-large-v3", audioPath, prompt, language)
+}
 
+func transcribeAudioGroq(ctx context.Context, apiKey, model, audioPath, prompt, language string) (string, error) {
 	// Read audio file
 	fileData, err := os.ReadFile(audioPath)
 	if err != nil {
@@ -38,7 +64,10 @@ func TranscribeAudioGroq(audioPath, prompt, language string) (string, error) {
 	}
 
 	// Add other fields
-	w.WriteField("model", "whisper-large-v3") // Adjust model logic as needed
+	if model == "" {
+		model = "whisper-large-v3"
+	}
+	w.WriteField("model", model)
 	if prompt != "" {
 		w.WriteField("prompt", prompt)
 	}
@@ -50,7 +79,7 @@ func TranscribeAudioGroq(audioPath, prompt, language string) (string, error) {
 
 	w.Close()
 
-	req, err := http.NewRequest("POST", "https://api.groq.com/v1/audio/transcriptions", &b)
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.groq.com/openai/v1/audio/transcriptions", &b)
 	if err != nil {
 		return "", err
 	}
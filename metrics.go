@@ -0,0 +1,92 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	// transcriptionsTotal and transcriptionDuration are recorded per backend call, not per voice
+	// note: a chunked voice note makes one backend call per chunk, so it's counted N times here
+	// while audioLengthSeconds below only observes it once. Don't read transcriptions_total as
+	// "number of voice notes processed".
+	transcriptionsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "transcriptions_total",
+		Help: "Total number of transcription backend calls, by provider, status and language. Chunked voice notes count once per chunk, not once per message.",
+	}, []string{"provider", "status", "lang"})
+
+	transcriptionDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "transcription_duration_seconds",
+		Help: "Time spent in a single transcription backend call, by provider. Chunked voice notes are observed once per chunk, not once per message.",
+	}, []string{"provider"})
+
+	audioLengthSeconds = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "audio_length_seconds",
+		Help:    "Length of incoming voice notes.",
+		Buckets: prometheus.ExponentialBuckets(1, 2, 10),
+	})
+
+	activeWorkers = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "active_workers",
+		Help: "Number of transcriptions currently in flight.",
+	})
+
+	whatsmeowConnected = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "whatsmeow_connected",
+		Help: "Whether the whatsmeow client is currently connected to WhatsApp (1) or not (0).",
+	})
+)
+
+// inFlight tracks transcriptions that must finish before shutdown can proceed.
+var inFlight sync.WaitGroup
+
+// ready flips to true once the client has connected at least once, gating /readyz.
+var ready atomic.Bool
+
+// startMetricsServer exposes /metrics, /healthz and /readyz on addr. The returned server should
+// be shut down during graceful shutdown.
+func startMetricsServer(addr string) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if !ready.Load() {
+			http.Error(w, "not ready", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+
+	server := &http.Server{Addr: addr, Handler: mux}
+	go func() {
+		slog.Info("starting metrics server", "addr", addr)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			slog.Error("metrics server stopped", "error", err)
+		}
+	}()
+	return server
+}
+
+// drainInFlight waits for in-flight transcriptions to finish, up to timeout.
+func drainInFlight(timeout time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		inFlight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		slog.Warn("timed out waiting for in-flight transcriptions to finish")
+	}
+}
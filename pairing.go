@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"image/png"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/mdp/qrterminal/v3"
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/types/events"
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// PAIR_HTTP_ADDR, when set, exposes the pairing QR code as a PNG at /pair for headless
+// servers where scanning a terminal QR code isn't practical.
+var PAIR_HTTP_ADDR = os.Getenv("PAIR_HTTP_ADDR")
+
+var lastQRCode string
+
+// connectClientWithPairing connects the client, running the first-run QR pairing flow if the
+// store doesn't already hold a registered device. For a registered device it retries a failed
+// Connect with exponential backoff until one succeeds; the pairing path does not retry.
+func connectClientWithPairing(ctx context.Context, client *whatsmeow.Client) error {
+	if client.Store.ID == nil {
+		return pairNewDevice(ctx, client)
+	}
+
+	backoff := time.Second
+	for {
+		if err := client.Connect(); err != nil {
+			log.Printf("Failed to connect: %v, retrying in %s", err, backoff)
+			time.Sleep(backoff)
+			if backoff < 30*time.Second {
+				backoff *= 2
+			}
+			continue
+		}
+		return nil
+	}
+}
+
+// pairNewDevice runs the QR pairing flow described in whatsmeow's mdtest example: print the
+// QR code to the terminal and, if PAIR_HTTP_ADDR is set, also serve it as a PNG over HTTP.
+func pairNewDevice(ctx context.Context, client *whatsmeow.Client) error {
+	qrChan, err := client.GetQRChannel(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get QR channel: %w", err)
+	}
+
+	if PAIR_HTTP_ADDR != "" {
+		go servePairingHTTP(PAIR_HTTP_ADDR)
+	}
+
+	if err := client.Connect(); err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+
+	for evt := range qrChan {
+		switch evt.Event {
+		case "code":
+			lastQRCode = evt.Code
+			fmt.Println("Scan the QR code below with WhatsApp to pair this device:")
+			qrterminal.GenerateHalfBlock(evt.Code, qrterminal.L, os.Stdout)
+		case "success":
+			log.Println("Pairing successful")
+		case "timeout":
+			return fmt.Errorf("pairing timed out, restart to try again")
+		}
+	}
+	return nil
+}
+
+// servePairingHTTP exposes the current pairing QR code as a PNG at /pair for headless setups.
+func servePairingHTTP(addr string) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/pair", func(w http.ResponseWriter, r *http.Request) {
+		if lastQRCode == "" {
+			http.Error(w, "no pairing QR code available", http.StatusNotFound)
+			return
+		}
+		qr, err := qrcode.New(lastQRCode, qrcode.Medium)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		png.Encode(w, qr.Image(256))
+	})
+	log.Printf("Serving pairing QR code at http://%s/pair", addr)
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		log.Printf("Pairing HTTP server stopped: %v", err)
+	}
+}
+
+// handlePairEvents reacts to pairing and session lifecycle events.
+func handlePairEvents(client *whatsmeow.Client, evt interface{}) {
+	switch evt.(type) {
+	case *events.PairSuccess:
+		log.Println("Device paired successfully")
+	case *events.LoggedOut:
+		log.Println("Device was logged out remotely, clearing local session")
+		logoutDevice(client)
+	}
+}
+
+// logoutDevice clears the paired device from the store so the next run starts pairing again.
+func logoutDevice(client *whatsmeow.Client) {
+	if client.Store != nil {
+		if err := client.Store.Delete(); err != nil {
+			log.Printf("Failed to clear device store: %v", err)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+// Package audio chunks long voice notes into silence-aligned segments so they fit the
+// size/duration limits enforced by hosted transcription APIs.
+package audio
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+const (
+	// DefaultMaxBytes is the file size above which audio is chunked before transcription.
+	DefaultMaxBytes int64 = 25 * 1024 * 1024
+	// DefaultMaxDuration is the duration above which audio is chunked before transcription.
+	DefaultMaxDuration = 10 * time.Minute
+	// DefaultChunkDuration is the target length of each chunk.
+	DefaultChunkDuration = 60 * time.Second
+	// DefaultSilenceNoiseDB and DefaultSilenceMinDur are the silencedetect parameters used to
+	// find safe cut points.
+	DefaultSilenceNoiseDB = "-30dB"
+	DefaultSilenceMinDur  = 0.5
+)
+
+// Config controls when and how audio is chunked.
+type Config struct {
+	MaxBytes       int64
+	MaxDuration    time.Duration
+	ChunkDuration  time.Duration
+	SilenceNoiseDB string
+	SilenceMinDur  float64
+}
+
+// DefaultConfig returns the Config used when the caller doesn't override anything.
+func DefaultConfig() Config {
+	return Config{
+		MaxBytes:       DefaultMaxBytes,
+		MaxDuration:    DefaultMaxDuration,
+		ChunkDuration:  DefaultChunkDuration,
+		SilenceNoiseDB: DefaultSilenceNoiseDB,
+		SilenceMinDur:  DefaultSilenceMinDur,
+	}
+}
+
+// Chunk is one silence-aligned slice of the original audio.
+type Chunk struct {
+	Path  string
+	Start time.Duration
+	End   time.Duration
+}
+
+// silenceRange is a detected span of silence, used as a candidate cut point.
+type silenceRange struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+// NeedsChunking reports whether the audio at path exceeds the configured size or duration
+// ceiling and should be split before being sent to a transcription backend.
+func NeedsChunking(ctx context.Context, path string, cfg Config) (bool, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, fmt.Errorf("stat audio file: %w", err)
+	}
+	if info.Size() > cfg.MaxBytes {
+		return true, nil
+	}
+
+	duration, err := probeDuration(ctx, path)
+	if err != nil {
+		// ffprobe is only needed to catch the duration ceiling; a missing or misbehaving
+		// binary shouldn't fail every short voice note that never needed chunking in the
+		// first place. Proceed as if it doesn't need chunking and let the size check above
+		// still catch oversized files.
+		slog.Warn("failed to probe audio duration, proceeding without duration-based chunking", "path", path, "error", err)
+		return false, nil
+	}
+	return duration > cfg.MaxDuration, nil
+}
+
+// probeDuration reads the audio duration via ffprobe.
+func probeDuration(ctx context.Context, path string) (time.Duration, error) {
+	cmd := exec.CommandContext(ctx, "ffprobe", "-v", "error", "-show_entries", "format=duration", "-of", "csv=p=0", path)
+	out, err := cmd.Output()
+	if err != nil {
+		return 0, fmt.Errorf("ffprobe: %w", err)
+	}
+	seconds, err := strconv.ParseFloat(string(bytesTrimSpace(out)), 64)
+	if err != nil {
+		return 0, fmt.Errorf("parse ffprobe duration: %w", err)
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+func bytesTrimSpace(b []byte) []byte {
+	start, end := 0, len(b)
+	for start < end && (b[start] == ' ' || b[start] == '\n' || b[start] == '\r' || b[start] == '\t') {
+		start++
+	}
+	for end > start && (b[end-1] == ' ' || b[end-1] == '\n' || b[end-1] == '\r' || b[end-1] == '\t') {
+		end--
+	}
+	return b[start:end]
+}
+
+// TranscodeToWAV converts the input audio to 16kHz mono WAV, the format the silence detector
+// and most transcription backends expect.
+func TranscodeToWAV(ctx context.Context, inputPath, outputPath string) error {
+	cmd := exec.CommandContext(ctx, "ffmpeg", "-y", "-i", inputPath, "-ar", "16000", "-ac", "1", outputPath)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("ffmpeg transcode: %w: %s", err, out)
+	}
+	return nil
+}
+
+var silenceStartRe = regexp.MustCompile(`silence_start:\s*([0-9.]+)`)
+var silenceEndRe = regexp.MustCompile(`silence_end:\s*([0-9.]+)`)
+
+// detectSilences runs ffmpeg's silencedetect filter and parses the silence spans from stderr.
+func detectSilences(ctx context.Context, path string, cfg Config) ([]silenceRange, error) {
+	filter := fmt.Sprintf("silencedetect=noise=%s:d=%g", cfg.SilenceNoiseDB, cfg.SilenceMinDur)
+	cmd := exec.CommandContext(ctx, "ffmpeg", "-i", path, "-af", filter, "-f", "null", "-")
+
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("ffmpeg silencedetect: %w", err)
+	}
+
+	var silences []silenceRange
+	var pendingStart time.Duration
+	haveStart := false
+
+	scanner := bufio.NewScanner(stderr)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if m := silenceStartRe.FindStringSubmatch(line); m != nil {
+			if seconds, err := strconv.ParseFloat(m[1], 64); err == nil {
+				pendingStart = time.Duration(seconds * float64(time.Second))
+				haveStart = true
+			}
+		} else if m := silenceEndRe.FindStringSubmatch(line); m != nil && haveStart {
+			if seconds, err := strconv.ParseFloat(m[1], 64); err == nil {
+				silences = append(silences, silenceRange{Start: pendingStart, End: time.Duration(seconds * float64(time.Second))})
+				haveStart = false
+			}
+		}
+	}
+
+	if err := cmd.Wait(); err != nil {
+		// silencedetect always exits 0 when decoding succeeds; a non-zero exit means decoding failed.
+		return nil, fmt.Errorf("ffmpeg silencedetect: %w", err)
+	}
+	return silences, nil
+}
+
+// planCuts turns detected silence spans into chunk boundaries no longer than maxChunkDuration.
+// For each chunk it prefers cutting in the middle of the latest silence that still falls within
+// the chunk's length budget; if no silence falls in that window (sparse pauses, or none detected
+// at all), it forces a cut at exactly maxChunkDuration so no chunk is ever longer than that.
+func planCuts(silences []silenceRange, totalDuration, maxChunkDuration time.Duration) []time.Duration {
+	if maxChunkDuration <= 0 {
+		return nil
+	}
+
+	var cuts []time.Duration
+	chunkStart := time.Duration(0)
+	for chunkStart+maxChunkDuration < totalDuration {
+		limit := chunkStart + maxChunkDuration
+
+		// silences are reported by ffmpeg in chronological order, so the first one whose
+		// midpoint falls beyond limit means none of the rest fit either.
+		best := time.Duration(-1)
+		for _, s := range silences {
+			if s.Start <= chunkStart {
+				continue
+			}
+			mid := s.Start + (s.End-s.Start)/2
+			if mid > limit {
+				break
+			}
+			best = mid
+		}
+
+		cut := limit
+		if best >= 0 {
+			cut = best
+		}
+		cuts = append(cuts, cut)
+		chunkStart = cut
+	}
+	return cuts
+}
+
+// Split transcodes the input to 16kHz mono WAV, detects silence boundaries and cuts it into
+// chunks no longer than cfg.ChunkDuration, cut at silences where planCuts finds one in the
+// window and at the length limit otherwise.
+func Split(ctx context.Context, inputPath, workDir string, cfg Config) ([]Chunk, error) {
+	wavPath := filepath.Join(workDir, "transcode.wav")
+	if err := TranscodeToWAV(ctx, inputPath, wavPath); err != nil {
+		return nil, err
+	}
+
+	duration, err := probeDuration(ctx, wavPath)
+	if err != nil {
+		return nil, err
+	}
+
+	silences, err := detectSilences(ctx, wavPath, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	cuts := planCuts(silences, duration, cfg.ChunkDuration)
+
+	bounds := append([]time.Duration{0}, cuts...)
+	bounds = append(bounds, duration)
+
+	chunks := make([]Chunk, 0, len(bounds)-1)
+	for i := 0; i < len(bounds)-1; i++ {
+		start, end := bounds[i], bounds[i+1]
+		if end <= start {
+			continue
+		}
+		chunkPath := filepath.Join(workDir, fmt.Sprintf("chunk-%03d.wav", i))
+		cmd := exec.CommandContext(ctx, "ffmpeg", "-y", "-ss", fmt.Sprintf("%f", start.Seconds()),
+			"-t", fmt.Sprintf("%f", (end - start).Seconds()), "-i", wavPath, chunkPath)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return nil, fmt.Errorf("ffmpeg cut chunk %d: %w: %s", i, err, out)
+		}
+		chunks = append(chunks, Chunk{Path: chunkPath, Start: start, End: end})
+	}
+	return chunks, nil
+}
@@ -0,0 +1,87 @@
+package audio
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPlanCuts(t *testing.T) {
+	sec := time.Second
+
+	tests := []struct {
+		name             string
+		silences         []silenceRange
+		totalDuration    time.Duration
+		maxChunkDuration time.Duration
+		want             []time.Duration
+	}{
+		{
+			name:             "shorter than max chunk duration needs no cuts",
+			silences:         nil,
+			totalDuration:    30 * sec,
+			maxChunkDuration: 60 * sec,
+			want:             nil,
+		},
+		{
+			name:             "no silence detected forces a fixed-interval cut",
+			silences:         nil,
+			totalDuration:    150 * sec,
+			maxChunkDuration: 60 * sec,
+			want:             []time.Duration{60 * sec, 120 * sec},
+		},
+		{
+			name: "cuts at the midpoint of a silence within the chunk window",
+			silences: []silenceRange{
+				{Start: 58 * sec, End: 59 * sec},
+			},
+			totalDuration:    100 * sec,
+			maxChunkDuration: 60 * sec,
+			want:             []time.Duration{58500 * time.Millisecond},
+		},
+		{
+			name: "sparse pause far beyond the window still forces a cut at the limit",
+			silences: []silenceRange{
+				{Start: 200 * sec, End: 201 * sec},
+			},
+			totalDuration:    300 * sec,
+			maxChunkDuration: 60 * sec,
+			want: []time.Duration{
+				60 * sec, 120 * sec, 180 * sec,
+				200500 * time.Millisecond,
+				260500 * time.Millisecond,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := planCuts(tt.silences, tt.totalDuration, tt.maxChunkDuration)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("planCuts() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTail(t *testing.T) {
+	tests := []struct {
+		name     string
+		s        string
+		maxRunes int
+		want     string
+	}{
+		{name: "shorter than limit returns as-is", s: "hello", maxRunes: 10, want: "hello"},
+		{name: "exact length returns as-is", s: "hello", maxRunes: 5, want: "hello"},
+		{name: "longer than limit keeps the tail", s: "hello world", maxRunes: 5, want: "world"},
+		{name: "empty string", s: "", maxRunes: 5, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tail(tt.s, tt.maxRunes); got != tt.want {
+				t.Errorf("tail(%q, %d) = %q, want %q", tt.s, tt.maxRunes, got, tt.want)
+			}
+		})
+	}
+}
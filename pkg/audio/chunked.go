@@ -0,0 +1,64 @@
+package audio
+
+import (
+	"context"
+	"os"
+	"strings"
+)
+
+// TranscribeFunc transcribes a single chunk, given the running prompt carried over from the
+// previous chunk's tail (Whisper-style prompt chaining).
+type TranscribeFunc func(ctx context.Context, chunkPath, prompt string) (string, error)
+
+// Segment is one chunk's transcript, in chunk order.
+type Segment struct {
+	Chunk Chunk
+	Text  string
+}
+
+// TranscribeChunked splits inputPath into silence-aligned chunks under workDir and transcribes
+// them in order, each one's prompt being the previous chunk's transcript tail (seeded from
+// basePrompt). Chunks are transcribed one at a time, not through a worker pool: prompt chaining
+// is inherently sequential, since chunk i's prompt only exists once chunk i-1 has actually
+// finished, so bounded concurrency across chunks isn't something this function can offer.
+func TranscribeChunked(ctx context.Context, inputPath, workDir, basePrompt string, cfg Config, transcribe TranscribeFunc) (string, []Segment, error) {
+	chunks, err := Split(ctx, inputPath, workDir, cfg)
+	if err != nil {
+		return "", nil, err
+	}
+	defer func() {
+		for _, c := range chunks {
+			os.Remove(c.Path)
+		}
+	}()
+
+	segments := make([]Segment, len(chunks))
+	prompt := basePrompt
+
+	for i, chunk := range chunks {
+		text, err := transcribe(ctx, chunk.Path, prompt)
+		if err != nil {
+			return "", nil, err
+		}
+		segments[i] = Segment{Chunk: chunk, Text: text}
+		prompt = tail(text, 200)
+	}
+
+	var b strings.Builder
+	for i, seg := range segments {
+		if i > 0 {
+			b.WriteString(" ")
+		}
+		b.WriteString(strings.TrimSpace(seg.Text))
+	}
+	return strings.TrimSpace(b.String()), segments, nil
+}
+
+// tail returns the last maxRunes runes of s, used to seed the next chunk's prompt.
+func tail(s string, maxRunes int) string {
+	runes := []rune(s)
+	if len(runes) <= maxRunes {
+		return s
+	}
+	return string(runes[len(runes)-maxRunes:])
+}
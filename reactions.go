@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+	waProto "go.mau.fi/whatsmeow/binary/proto"
+	"go.mau.fi/whatsmeow/types/events"
+	"google.golang.org/protobuf/proto"
+)
+
+// Emoji reactions used to signal transcription progress, mirroring the message-ID+sender scheme
+// matterbridge's whatsmeow reply fix uses for ReactionMessage.
+const (
+	reactionPending = "⏳"
+	reactionSuccess = "✅"
+	reactionFailure = "❌"
+)
+
+// sendReaction reacts to the original message with emoji. Reactions are opt-in per chat via
+// settings.ReactionsEnabled.
+func sendReaction(client *whatsmeow.Client, evt *events.Message, settings ChatSettings, emoji string) {
+	if !settings.ReactionsEnabled {
+		return
+	}
+
+	key := &waProto.MessageKey{
+		RemoteJID:   proto.String(evt.Info.Chat.String()),
+		FromMe:      proto.Bool(false),
+		ID:          proto.String(evt.Info.ID),
+		Participant: proto.String(evt.Info.Sender.String()),
+	}
+	reaction := &waProto.Message{
+		ReactionMessage: &waProto.ReactionMessage{
+			Key:               key,
+			Text:              proto.String(emoji),
+			SenderTimestampMS: proto.Int64(time.Now().UnixMilli()),
+		},
+	}
+
+	if _, err := client.SendMessage(context.Background(), evt.Info.Chat, reaction); err != nil {
+		log.Printf("Failed to send %s reaction: %v", emoji, err)
+	}
+}
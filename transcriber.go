@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gustavokch/whatsapp_transcriber_go/pkg/audio"
+)
+
+// TranscribeOptions carries the per-request parameters shared by every transcription backend.
+type TranscribeOptions struct {
+	Prompt   string
+	Language string
+}
+
+// TranscribeResult is the normalized output of a Transcriber.
+type TranscribeResult struct {
+	Text     string
+	Provider string
+}
+
+// Transcriber is implemented by every speech-to-text backend (Groq, Cloudflare, Deepgram, ...).
+type Transcriber interface {
+	// Name identifies the backend for logging and routing.
+	Name() string
+	Transcribe(ctx context.Context, audioPath string, opts TranscribeOptions) (TranscribeResult, error)
+}
+
+// transcriberByName builds a Transcriber from its credentials in the environment, matching the
+// provider names used by TRANSCRIBER_PRIMARY/TRANSCRIBER_FALLBACK.
+func transcriberByName(name string) Transcriber {
+	switch name {
+	case "groq":
+		return NewGroqTranscriber(os.Getenv("GROQ_API_KEY"))
+	case "cloudflare":
+		return NewCloudflareTranscriber(os.Getenv("CF_ACCOUNT_ID"), os.Getenv("CF_API_KEY"), "", "")
+	case "deepgram":
+		return NewDeepgramTranscriber(os.Getenv("DEEPGRAM_API_KEY"))
+	default:
+		return nil
+	}
+}
+
+// cloudflareSizeThreshold is the file size below which the auto router prefers Cloudflare, which
+// is cheaper but less reliable on longer audio, over Groq.
+const cloudflareSizeThreshold = 1 << 20 // 1MB
+
+// autoPrimary picks a primary backend based on the audio file size: Cloudflare for small files,
+// Groq otherwise.
+func autoPrimary(audioPath string) string {
+	if info, err := os.Stat(audioPath); err == nil && info.Size() < cloudflareSizeThreshold {
+		return "cloudflare"
+	}
+	return "groq"
+}
+
+// BuildTranscriberChain assembles the TranscriberChain for a given audio file from the
+// TRANSCRIBER_PRIMARY and TRANSCRIBER_FALLBACK environment variables. TRANSCRIBER_PRIMARY may be
+// set to "auto" to route by file size instead of a fixed provider. TRANSCRIBER_FALLBACK is a
+// comma-separated list tried in order if the primary fails.
+func BuildTranscriberChain(audioPath string) *TranscriberChain {
+	primary := os.Getenv("TRANSCRIBER_PRIMARY")
+	switch primary {
+	case "", "auto":
+		primary = autoPrimary(audioPath)
+	}
+
+	var chain []Transcriber
+	if t := transcriberByName(primary); t != nil {
+		chain = append(chain, t)
+	}
+
+	fallback := os.Getenv("TRANSCRIBER_FALLBACK")
+	if fallback == "" {
+		fallback = "deepgram"
+	}
+	for _, name := range strings.Split(fallback, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" || name == primary {
+			continue
+		}
+		if t := transcriberByName(name); t != nil {
+			chain = append(chain, t)
+		}
+	}
+
+	return NewTranscriberChain(chain...)
+}
+
+// TranscriberChain tries a list of Transcribers in order, falling through to the next one on
+// error or timeout.
+type TranscriberChain struct {
+	Transcribers []Transcriber
+}
+
+func NewTranscriberChain(transcribers ...Transcriber) *TranscriberChain {
+	return &TranscriberChain{Transcribers: transcribers}
+}
+
+// transcribeAudio transcribes audioPath, chunking it first if it exceeds the size/duration
+// ceiling that Groq and Cloudflare both reject. It returns the transcript and the name of the
+// provider that produced it ("chunked" when more than one provider may have been involved).
+func transcribeAudio(ctx context.Context, audioPath, prompt, language string) (string, string, error) {
+	cfg := audio.DefaultConfig()
+	needsChunking, err := audio.NeedsChunking(ctx, audioPath, cfg)
+	if err != nil {
+		return "", "", fmt.Errorf("check audio length: %w", err)
+	}
+	if !needsChunking {
+		chain := BuildTranscriberChain(audioPath)
+		result, err := chain.Transcribe(ctx, audioPath, TranscribeOptions{Prompt: prompt, Language: language})
+		if err != nil {
+			return "", "", err
+		}
+		return result.Text, result.Provider, nil
+	}
+
+	workDir, err := os.MkdirTemp("", "audio-chunks-*")
+	if err != nil {
+		return "", "", fmt.Errorf("create chunk workdir: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	text, _, err := audio.TranscribeChunked(ctx, audioPath, workDir, prompt, cfg, func(ctx context.Context, chunkPath, chunkPrompt string) (string, error) {
+		chain := BuildTranscriberChain(chunkPath)
+		result, err := chain.Transcribe(ctx, chunkPath, TranscribeOptions{Prompt: chunkPrompt, Language: language})
+		if err != nil {
+			return "", err
+		}
+		return result.Text, nil
+	})
+	return text, "chunked", err
+}
+
+// Transcribe tries each backend in order, recording transcriptions_total and
+// transcription_duration_seconds for every attempt.
+func (c *TranscriberChain) Transcribe(ctx context.Context, audioPath string, opts TranscribeOptions) (TranscribeResult, error) {
+	if len(c.Transcribers) == 0 {
+		return TranscribeResult{}, fmt.Errorf("no transcription backends configured")
+	}
+
+	var lastErr error
+	for _, t := range c.Transcribers {
+		start := time.Now()
+		result, err := t.Transcribe(ctx, audioPath, opts)
+		transcriptionDuration.WithLabelValues(t.Name()).Observe(time.Since(start).Seconds())
+
+		if err == nil {
+			result.Provider = t.Name()
+			transcriptionsTotal.WithLabelValues(t.Name(), "success", opts.Language).Inc()
+			return result, nil
+		}
+		transcriptionsTotal.WithLabelValues(t.Name(), "error", opts.Language).Inc()
+		lastErr = fmt.Errorf("%s: %w", t.Name(), err)
+	}
+	return TranscribeResult{}, fmt.Errorf("all transcription backends failed, last error: %w", lastErr)
+}
@@ -1,48 +1,36 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
-	"io"
 	"log"
+	"log/slog"
 	"os"
 	"os/signal"
-	"path/filepath"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/joho/godotenv"
 	"go.mau.fi/whatsmeow"
+	waProto "go.mau.fi/whatsmeow/binary/proto"
 	"go.mau.fi/whatsmeow/store/sqlstore"
 	"go.mau.fi/whatsmeow/types/events"
 	waLog "go.mau.fi/whatsmeow/util/log"
-	// Example - for core WhatsApp types
-	// Example - for media related types
-	// Example - for connection/call related types)
+	"google.golang.org/protobuf/proto"
 )
 
 var (
-	EXCLUDED_NUMBERS map[string]bool
-	LOG_DIR          = "logs"
-	MESSAGES_DIR     = "messages"
-	WHISPER_PROMPT   = `Transcreva com precisão, preservando enunciados conforme falados. Corrija erros ortográficos comuns sem alterar a intenção original. Use pontuação e capitalização de forma natural para facilitar a leitura. Foda-se. Amorzinho.`
-)
+	LOG_DIR        = "logs"
+	MESSAGES_DIR   = "messages"
+	WHISPER_PROMPT = `Transcreva com precisão, preservando enunciados conforme falados. Corrija erros ortográficos comuns sem alterar a intenção original. Use pontuação e capitalização de forma natural para facilitar a leitura. Foda-se. Amorzinho.`
 
-func loadExcludedNumbers(filePath string) map[string]bool {
-	excluded := make(map[string]bool)
-	data, err := os.ReadFile(filePath)
-	if err != nil {
-		fmt.Printf("Warning: %s not found. Using default exclusions.\n", filePath)
-		return excluded
-	}
-	lines := strings.Split(string(data), "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line != "" {
-			excluded[line] = true
-		}
-	}
-	return excluded
-}
+	// numberACL replaces the old EXCLUDED_NUMBERS global with a reloadable allow/deny list.
+	numberACL *ACL
+	// chatConfig stores per-chat opt-in/opt-out and language/prompt overrides.
+	chatConfig *ChatConfigStore
+)
 
 func setupDirectories() {
 	dirs := []string{LOG_DIR, MESSAGES_DIR}
@@ -55,6 +43,9 @@ func setupDirectories() {
 }
 
 func main() {
+	logoutFlag := flag.Bool("logout", false, "clear the paired device from the store and exit")
+	flag.Parse()
+
 	// Load environment variables from .env
 	if err := godotenv.Load(); err != nil {
 		log.Println("No .env file found, continuing with environment variables")
@@ -64,13 +55,24 @@ func main() {
 
 	setupDirectories()
 
-	EXCLUDED_NUMBERS = loadExcludedNumbers("exclude.txt")
-	log.Printf("Loaded excluded numbers: %v", EXCLUDED_NUMBERS)
+	var err error
+	numberACL, err = LoadACL("exclude.txt")
+	if err != nil {
+		log.Fatalf("Failed to load ACL: %v", err)
+	}
+	chatConfig, err = NewChatConfigStore("chatconfig.sqlite3")
+	if err != nil {
+		log.Fatalf("Failed to open chat config store: %v", err)
+	}
+	watchForReload("exclude.txt")
+
+	metricsAddr := os.Getenv("METRICS_ADDR")
+	if metricsAddr == "" {
+		metricsAddr = ":9090"
+	}
+	metricsServer := startMetricsServer(metricsAddr)
 
 	// Initialize WhatsMeow client with sqlite storage (adjust DSN as needed)
-	// dbLog := log.New(os.Stdout, "DB: ", log.LstdFlags)
-	// waLog := log.New(os.Stdout, "WhatsApp: ", log.LstdFlags)
-	// waLog.Logger=logger.Info
 	container, err := sqlstore.New("sqlite3", "file:db.sqlite3?_foreign_keys=on", dbLog)
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
@@ -81,17 +83,21 @@ func main() {
 	}
 	client := whatsmeow.NewClient(deviceStore, clientLog)
 
-	// Register event handler for incoming messages
+	if *logoutFlag {
+		logoutDevice(client)
+		log.Println("Device cleared, run again to re-pair")
+		return
+	}
+
+	// Register event handler for incoming messages, connection state and pairing events.
 	client.AddEventHandler(func(evt interface{}) {
-		switch v := evt.(type) {
-		case *events.Message:
-			// Handle each message in its own goroutine
-			go handleMessage(client, v)
-		}
+		eventHandler(client, evt)
+		handlePairEvents(client, evt)
 	})
 
-	// Connect to WhatsApp
-	if err := client.Connect(); err != nil {
+	// Connect to WhatsApp, running the QR pairing flow on first run and retrying failed
+	// connection attempts with exponential backoff on later runs.
+	if err := connectClientWithPairing(context.Background(), client); err != nil {
 		log.Fatalf("Failed to connect: %v", err)
 	}
 	log.Println("Connected to WhatsApp")
@@ -101,113 +107,242 @@ func main() {
 	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
 	<-stop
 
-	log.Println("Shutting down...")
+	log.Println("Shutting down, draining in-flight transcriptions...")
+	drainInFlight(30 * time.Second)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := metricsServer.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Failed to shut down metrics server: %v", err)
+	}
+
 	client.Disconnect()
 }
 
-// handleMessage inspects incoming messages and routes audio messages for transcription.
+// eventHandler dispatches whatsmeow events, mirroring the switch-based dispatch used by
+// matterbridge's whatsappmulti bridge.
+func eventHandler(client *whatsmeow.Client, evt interface{}) {
+	switch v := evt.(type) {
+	case *events.Message:
+		// Handle each message in its own goroutine
+		go handleMessage(client, v)
+	case *events.Connected:
+		whatsmeowConnected.Set(1)
+		ready.Store(true)
+		log.Println("Connected to WhatsApp")
+	case *events.Disconnected:
+		whatsmeowConnected.Set(0)
+	case *events.LoggedOut:
+		log.Println("Device logged out, please re-pair")
+	case *events.QR:
+		log.Println("QR code received, scan it to pair this device")
+	}
+}
+
+// handleMessage inspects incoming messages, routes in-chat "!transcribe" admin commands, and
+// dispatches audio messages for transcription in both direct chats and groups.
 func handleMessage(client *whatsmeow.Client, evt *events.Message) {
-	if evt.Info.MediaType == "audio" && evt.Message.AudioMessage != nil {
-		// Get the media key and direct path
-		audioMsg := evt.Message.AudioMessage
-		mediaKey := audioMsg.GetMediaKey()
-		directPath := audioMsg.GetDirectPath()
-
-		// Define the file path where the audio will be saved
-		savePath := filepath.Join("downloads", fmt.Sprintf("%s.ogg", evt.Info.ID))
-
-		// Ensure the directory exists
-		if err := os.MkdirAll(filepath.Dir(savePath), 0755); err != nil {
-			fmt.Println("Error creating directory:", err)
-			return
-		}
+	chatJID := evt.Info.Chat.String()
+	logCtx := slog.With("chat_jid", chatJID, "msg_id", evt.Info.ID)
 
-		// Save the file
-		err = os.WriteFile(savePath, data, 0644)
-		if err != nil {
-			fmt.Println("Error saving audio file:", err)
+	// Check if sender is excluded. This gates admin commands as well as transcription: a
+	// deny-listed number shouldn't be able to drive per-chat config any more than it can
+	// request a transcription.
+	sender := evt.Info.Sender.User
+	if !numberACL.Allowed(sender) {
+		logCtx.Info("sender not allowed, skipping message", "sender", sender)
+		return
+	}
+
+	text := evt.Message.GetConversation()
+	if text == "" {
+		text = evt.Message.GetExtendedTextMessage().GetText()
+	}
+	if text != "" {
+		if subcommand, args, ok := parseAdminCommand(text); ok {
+			handleAdminCommand(client, evt, chatJID, subcommand, args)
 			return
 		}
+	}
 
-		fmt.Println("Audio message saved to:", savePath)
+	settings, err := chatConfig.Get(chatJID)
+	if err != nil {
+		logCtx.Error("failed to load chat settings", "error", err)
+		return
 	}
-	// Skip group messages
-	if evt.Info.IsGroup {
-		log.Println("Message is from a group, ignoring...")
+	if !settings.Enabled {
+		logCtx.Info("transcription disabled for chat, ignoring")
 		return
 	}
 
-	// Check if sender is excluded
-	sender := evt.Info.Sender.User
-	if EXCLUDED_NUMBERS[sender] {
-		log.Printf("Sender %s is excluded. Skipping transcription.", sender)
+	// Audio notes can arrive either as a regular AudioMessage or as a push-to-talk message.
+	audioMsg := evt.Message.GetAudioMessage()
+	if audioMsg == nil {
+		audioMsg = evt.Message.GetPttMessage()
+	}
+	if audioMsg == nil {
+		return
+	}
+	if settings.MinDurationSec > 0 && int(audioMsg.GetSeconds()) < settings.MinDurationSec {
+		logCtx.Info("audio shorter than configured minimum, ignoring", "min_duration_sec", settings.MinDurationSec)
+		return
+	}
+
+	logCtx.Info("audio message detected, processing transcription")
+	if err := processAudioMessage(client, evt, audioMsg, settings); err != nil {
+		logCtx.Error("error processing audio message", "error", err)
+	}
+}
+
+// handleAdminCommand applies a "!transcribe ..." command, restricted to group admins in group
+// chats, and replies with the result.
+func handleAdminCommand(client *whatsmeow.Client, evt *events.Message, chatJID, subcommand, args string) {
+	if evt.Info.IsGroup && !isGroupAdmin(client, evt) {
+		log.Printf("Ignoring !transcribe command from non-admin %s in %s", evt.Info.Sender, chatJID)
 		return
 	}
 
-	// Check if message contains an audio message.
-	// (Adjust the field access based on WhatsMeow’s message structure.)
-	if audioMsg := evt.Message.GetAudioMessage(); audioMsg != nil {
-		log.Println("Audio message detected, processing transcription...")
-		if err := processAudioMessage(client, evt); err != nil {
-			log.Printf("Error processing audio message: %v", err)
+	settings, err := chatConfig.Get(chatJID)
+	if err != nil {
+		log.Printf("Failed to load chat settings for %s: %v", chatJID, err)
+		return
+	}
+
+	reply, err := applyAdminCommand(&settings, subcommand, args)
+	if err != nil {
+		sendReply(client, evt, fmt.Sprintf("Erro: %v", err))
+		return
+	}
+	if subcommand != "status" {
+		if err := chatConfig.Set(chatJID, settings); err != nil {
+			log.Printf("Failed to save chat settings for %s: %v", chatJID, err)
+			sendReply(client, evt, "Erro ao salvar configuração.")
+			return
 		}
-	} else {
-		log.Println("Received non-audio message, ignoring...")
 	}
+	sendReply(client, evt, reply)
 }
 
-// processAudioMessage downloads the audio, calls the transcription API, and sends a reply.
-func processAudioMessage(client *whatsmeow.Client, evt *events.Message) error {
-	audioMsg := client.DownloadToFile(evt.Message.GetAudioMessage(), File)
-	if audioMsg == nil {
-		return fmt.Errorf("audio message details not found")
+// isGroupAdmin reports whether the message sender is an admin of the group it was sent in.
+func isGroupAdmin(client *whatsmeow.Client, evt *events.Message) bool {
+	info, err := client.GetGroupInfo(evt.Info.Chat)
+	if err != nil {
+		log.Printf("Failed to fetch group info for %s: %v", evt.Info.Chat, err)
+		return false
+	}
+	for _, participant := range info.Participants {
+		if participant.JID.User == evt.Info.Sender.User {
+			return participant.IsAdmin || participant.IsSuperAdmin
+		}
 	}
+	return false
+}
+
+// processAudioMessage downloads and decrypts the voice note, transcribes it, and sends the
+// transcription back according to the chat's configured reply style, reacting with emoji along
+// the way when the chat has opted in.
+func processAudioMessage(client *whatsmeow.Client, evt *events.Message, audioMsg *waProto.AudioMessage, settings ChatSettings) error {
+	logCtx := slog.With("chat_jid", evt.Info.Chat.String(), "msg_id", evt.Info.ID, "attempt", 1)
 
-	// Example: assume audioMsg.URL contains the download link and audioMsg.FileLength is available.
+	inFlight.Add(1)
+	activeWorkers.Inc()
+	defer inFlight.Done()
+	defer activeWorkers.Dec()
 
-	// Download audio file (here using a simple HTTP GET; in production, use WhatsMeow’s media download if available)
+	audioLengthSeconds.Observe(float64(audioMsg.GetSeconds()))
+	sendReaction(client, evt, settings, reactionPending)
 
-	//	Create a temporary file in MESSAGES_DIR
-	tempFile, err := os.CreateTemp(MESSAGES_DIR, fmt.Sprintf("audio-%d-*.webm"))
+	data, err := client.Download(audioMsg)
 	if err != nil {
-		log.Printf("Failed to create temp file: %v", err)
-		return err
+		sendReaction(client, evt, settings, reactionFailure)
+		return fmt.Errorf("failed to download audio message: %w", err)
+	}
+
+	tempFile, err := os.CreateTemp(MESSAGES_DIR, fmt.Sprintf("audio-%s-*.ogg", evt.Info.ID))
+	if err != nil {
+		sendReaction(client, evt, settings, reactionFailure)
+		return fmt.Errorf("failed to create temp file: %w", err)
 	}
 	defer tempFile.Close()
 	tempFilePath := tempFile.Name()
+	defer os.Remove(tempFilePath)
 
-	if _, err := io.Copy(tempFile, audioMsg); err != nil {
-		log.Printf("Failed to save audio file: %v", err)
-		return err
+	if _, err := tempFile.Write(data); err != nil {
+		sendReaction(client, evt, settings, reactionFailure)
+		return fmt.Errorf("failed to save audio file: %w", err)
 	}
-	log.Printf("Audio message downloaded and saved to: %s", audioMsg)
+	logCtx.Info("audio message downloaded", "path", tempFilePath)
 
-	// Transcribe the audio using Groq (alternatively, you could call CfTranscribe)
-	transcription, err := TranscribeAudioGroq(audioMsg, WHISPER_PROMPT, "pt")
+	prompt := settings.Prompt
+	if prompt == "" {
+		prompt = WHISPER_PROMPT
+	}
+	text, provider, err := transcribeAudio(context.Background(), tempFilePath, prompt, settings.Language)
 	if err != nil {
-		log.Printf("Error during transcription: %v", err)
-		// Optionally, send a reply with an error message here.
-		return err
+		sendReaction(client, evt, settings, reactionFailure)
+		return fmt.Errorf("error during transcription: %w", err)
 	}
-	log.Println("Audio transcription completed.")
-
-	// Remove the temporary audio file
-	// if err := os.Remove(tempFilePath); err != nil {
-	// 	log.Printf("Error removing temporary audio file: %v", err)
-	// } else {
-	// 	log.Printf("Temporary audio file removed: %s", tempFilePath)
-	// }
+	logCtx.Info("audio transcription completed", "provider", provider)
 
-	// Prepare and send the reply (adjust based on WhatsMeow’s sending API)
-	transcription = strings.TrimSpace(transcription)
+	transcription := strings.TrimSpace(text)
 	replyText := fmt.Sprintf("*Transcrição automática:*\n\n_%s_", transcription)
-	chatID := evt.Info.Chat.ID // assuming Chat.ID is available
 
-	if err := client.SendMessage(chatID, replyText); err != nil {
+	sendTranscriptionReply(client, evt, audioMsg, settings, replyText)
+	sendReaction(client, evt, settings, reactionSuccess)
+	return nil
+}
+
+// quoteContextInfo builds the ContextInfo for a reply quoting evt. Participant is a group-quote
+// field and only means something when the quoted message came from a group chat; in a 1:1 DM
+// it's left unset.
+func quoteContextInfo(evt *events.Message, quoted *waProto.Message) *waProto.ContextInfo {
+	ctxInfo := &waProto.ContextInfo{
+		StanzaID:      proto.String(evt.Info.ID),
+		QuotedMessage: quoted,
+	}
+	if evt.Info.IsGroup {
+		ctxInfo.Participant = proto.String(evt.Info.Sender.String())
+	}
+	return ctxInfo
+}
+
+// sendTranscriptionReply sends the transcription text, quoting a stub of the original
+// AudioMessage rather than the full original message, and honoring the chat's reply_style
+// ("reply"/"quote" thread it in the original chat, "dm" sends it to the sender privately).
+func sendTranscriptionReply(client *whatsmeow.Client, evt *events.Message, audioMsg *waProto.AudioMessage, settings ChatSettings, text string) {
+	quotedStub := &waProto.Message{AudioMessage: audioMsg}
+	reply := &waProto.Message{
+		ExtendedTextMessage: &waProto.ExtendedTextMessage{
+			Text:        proto.String(text),
+			ContextInfo: quoteContextInfo(evt, quotedStub),
+		},
+	}
+
+	target := evt.Info.Chat
+	if settings.ReplyStyle == "dm" {
+		target = evt.Info.Sender
+	}
+
+	if _, err := client.SendMessage(context.Background(), target, reply); err != nil {
 		log.Printf("Failed to send reply message: %v", err)
-		return err
+		return
 	}
 	log.Println("Reply sent successfully.")
+}
 
-	return nil
+// sendReply sends plain text back to the chat the original message came from, quoting it as the
+// context message. Used for admin command responses.
+func sendReply(client *whatsmeow.Client, evt *events.Message, text string) {
+	reply := &waProto.Message{
+		ExtendedTextMessage: &waProto.ExtendedTextMessage{
+			Text:        proto.String(text),
+			ContextInfo: quoteContextInfo(evt, evt.Message),
+		},
+	}
+
+	if _, err := client.SendMessage(context.Background(), evt.Info.Chat, reply); err != nil {
+		log.Printf("Failed to send reply message: %v", err)
+		return
+	}
+	log.Println("Reply sent successfully.")
 }